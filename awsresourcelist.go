@@ -6,72 +6,79 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"regexp"
 	"strings"
-	"sync"
+	"syscall"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/jonboulle/clockwork"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
-	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
-	"github.com/aws/aws-sdk-go-v2/service/iam"
 )
 
 type awscfnresource struct {
 	PhysicalResourceId string
 	LogicalResourceId  string
+	Attempts           int
 }
 
 // Get all the resources from the Resource Group Tagging API
 
-func getallcfnresources(client *cloudformation.Client, stackID string) []awscfnresource {
-	// Initialise zap logging
-	logger, err := zap.NewProduction()
-	if err != nil {
-		log.Fatalf("can't initialize zap logger: %v", err)
-	}
-	defer logger.Sync()
-	sugar := logger.Sugar()
+func getallcfnresources(ctx context.Context, client *cloudformation.Client, stackID string, retriers []Retrier, metrics *Metrics, sugar *zap.SugaredLogger) []awscfnresource {
+	sugar = sugar.With(zap.String("stack", stackID))
 
-	// Get the list of resources from the CloudFormation API
+	// Get the list of resources from the CloudFormation API, paginating
+	// until exhausted instead of relying on DescribeStackResources' ~100
+	// resource cap.
 	awscfnresourceslice := []awscfnresource{}
-	output, err := client.DescribeStackResources(context.TODO(), &cloudformation.DescribeStackResourcesInput{
+	paginator := cloudformation.NewListStackResourcesPaginator(client, &cloudformation.ListStackResourcesInput{
 		StackName: aws.String(stackID),
 	})
-	if err != nil {
-		sugar.Fatalf("ListStackResources Error: %v", err)
-	}
-	for _, object := range output.StackResources {
-		sugar.Debugf("ARN=%s", aws.ToString(object.LogicalResourceId))
-		awscfnresourceslice = append(awscfnresourceslice, awscfnresource{*object.PhysicalResourceId, *object.LogicalResourceId})
-		iscloudformationARN, err := regexp.MatchString("^arn:aws:cloudformation:", *object.PhysicalResourceId)
-		if iscloudformationARN && err == nil {
-			sugar.Debugf("Found the nested stack")
-			awscfnresourceslice = append(awscfnresourceslice, getallcfnresources(client, *object.PhysicalResourceId)...)
-		} else {
-			sugar.Debugf("Not clouformation stack ARN")
+	for paginator.HasMorePages() {
+		if ctx.Err() != nil {
+			return awscfnresourceslice
+		}
+		var page *cloudformation.ListStackResourcesOutput
+		attempts, err := Do(ctx, retriers, func() error {
+			var callErr error
+			page, callErr = paginator.NextPage(ctx)
+			return callErr
+		})
+		if err != nil {
+			metrics.AddScanError(ErrorCode(err))
+			sugar.Errorf("ListStackResources Error after %d attempts: %v", attempts.Count, err)
+			return awscfnresourceslice
+		}
+		for _, object := range page.StackResourceSummaries {
+			sugar.Debugf("ARN=%s", aws.ToString(object.LogicalResourceId))
+			awscfnresourceslice = append(awscfnresourceslice, awscfnresource{*object.PhysicalResourceId, *object.LogicalResourceId, attempts.Count})
+			iscloudformationARN, err := regexp.MatchString("^arn:aws:cloudformation:", *object.PhysicalResourceId)
+			if iscloudformationARN && err == nil {
+				sugar.Debugf("Found the nested stack")
+				awscfnresourceslice = append(awscfnresourceslice, getallcfnresources(ctx, client, *object.PhysicalResourceId, retriers, metrics, sugar)...)
+			} else {
+				sugar.Debugf("Not clouformation stack ARN")
+			}
 		}
 	}
 	return awscfnresourceslice
 }
 
-func loadcsv(csvfile string) [][]string {
-	// Initialise zap logging
-	logger, err := zap.NewProduction()
-	if err != nil {
-		log.Fatalf("can't initialize zap logger: %v", err)
-	}
-	defer logger.Sync()
-	sugar := logger.Sugar()
-
-	// Load the CSV file
+// loadcsv reads and parses csvfile, returning an error instead of aborting
+// the process so a caller scanning many accounts/regions can decide to skip
+// enrichment for this one and keep going.
+func loadcsv(csvfile string, sugar *zap.SugaredLogger) ([][]string, error) {
 	csvfilehandle, err := os.Open(csvfile)
 	if err != nil {
-		sugar.Fatalf("Error opening CSV file: %v", err)
+		return nil, fmt.Errorf("opening CSV file: %w", err)
 	}
 	defer csvfilehandle.Close()
 	reader := csv.NewReader(csvfilehandle)
@@ -81,21 +88,13 @@ func loadcsv(csvfile string) [][]string {
 	reader.FieldsPerRecord = -1
 	record, err := reader.ReadAll()
 	if err != nil {
-		sugar.Fatalf("Error reading CSV file: %v", err)
+		return nil, fmt.Errorf("reading CSV file: %w", err)
 	}
 	sugar.Debugf("record %v", record)
-	return record
+	return record, nil
 }
 
-func searchfromrecord(record [][]string, search string) ([]string, int) {
-	// Initialise zap logging
-	logger, err := zap.NewProduction()
-	if err != nil {
-		log.Fatalf("can't initialize zap logger: %v", err)
-	}
-	defer logger.Sync()
-	sugar := logger.Sugar()
-
+func searchfromrecord(record [][]string, search string, sugar *zap.SugaredLogger) ([]string, int) {
 	// Search the CSV file for the search string
 	for countrecord, row := range record {
 		issearcharn, err := regexp.MatchString("^arn:.+$", search)
@@ -122,47 +121,49 @@ func searchfromrecord(record [][]string, search string) ([]string, int) {
 	return nil, -1
 }
 
-func removesliceentry(slice [][]string, i int) [][]string {
-	// Initialise zap logging
-	logger, err := zap.NewProduction()
-	if err != nil {
-		log.Fatalf("can't initialize zap logger: %v", err)
-	}
-	defer logger.Sync()
-	sugar := logger.Sugar()
-
+func removesliceentry(slice [][]string, i int, sugar *zap.SugaredLogger) [][]string {
 	// Remove the entry from the slice
 	sugar.Debugf("Removing entry %d", i)
 	slice = append(slice[:i], slice[i+1:]...)
 	return slice
 }
 
-func findallcloudformationstacks(client *cloudformation.Client, paginationToken string) []string {
-	// Initialise zap logging
-	logger, err := zap.NewProduction()
-	if err != nil {
-		log.Fatalf("can't initialize zap logger: %v", err)
+func findallcloudformationstacks(ctx context.Context, client *cloudformation.Client, paginationToken string, retriers []Retrier, metrics *Metrics, sugar *zap.SugaredLogger) []string {
+	if ctx.Err() != nil {
+		return []string{}
 	}
-	defer logger.Sync()
-	sugar := logger.Sugar()
 
 	// Get the list of resources from the CloudFormation API
 	stacklist := []string{}
 	var output *cloudformation.ListStacksOutput
+	var attempts Attempts
+	var err error
 
 	if paginationToken == "" {
-		output, err = client.ListStacks(context.TODO(), &cloudformation.ListStacksInput{
-			StackStatusFilter: []types.StackStatus{types.StackStatusCreateComplete, types.StackStatusUpdateComplete, types.StackStatusUpdateRollbackComplete},
+		attempts, err = Do(ctx, retriers, func() error {
+			var callErr error
+			output, callErr = client.ListStacks(ctx, &cloudformation.ListStacksInput{
+				StackStatusFilter: []types.StackStatus{types.StackStatusCreateComplete, types.StackStatusUpdateComplete, types.StackStatusUpdateRollbackComplete},
+			})
+			return callErr
 		})
 		if err != nil {
-			sugar.Fatalf("ListStackResources Error: %v", err)
+			metrics.AddScanError(ErrorCode(err))
+			sugar.Errorf("ListStacks Error after %d attempts: %v", attempts.Count, err)
+			return stacklist
 		}
 	} else {
-		output, err = client.ListStacks(context.TODO(), &cloudformation.ListStacksInput{
-			NextToken: aws.String(paginationToken),
+		attempts, err = Do(ctx, retriers, func() error {
+			var callErr error
+			output, callErr = client.ListStacks(ctx, &cloudformation.ListStacksInput{
+				NextToken: aws.String(paginationToken),
+			})
+			return callErr
 		})
 		if err != nil {
-			sugar.Fatalf("ListStackResources Error: %v", err)
+			metrics.AddScanError(ErrorCode(err))
+			sugar.Errorf("ListStacks Error after %d attempts: %v", attempts.Count, err)
+			return stacklist
 		}
 	}
 
@@ -174,93 +175,86 @@ func findallcloudformationstacks(client *cloudformation.Client, paginationToken
 		}
 	}
 	if output.NextToken != nil {
-		stacklist = append(stacklist, findallcloudformationstacks(client, aws.ToString(output.NextToken))...)
+		stacklist = append(stacklist, findallcloudformationstacks(ctx, client, aws.ToString(output.NextToken), retriers, metrics, sugar)...)
 	}
 	return stacklist
 }
 
-func findalliamroles(client *iam.Client, wg *sync.WaitGroup, iamrolelistchan chan<- [][]string) {
-	defer close(iamrolelistchan)
-	defer wg.Done()
-	// Initialise zap logging
-	logger, err := zap.NewProduction()
-	if err != nil {
-		log.Fatalf("can't initialize zap logger: %v", err)
-	}
-	defer logger.Sync()
-	sugar := logger.Sugar()
-	sugar.Infof("Finding all IAM roles function running")
-	// Get the list of resources from the IAM API
-	rolelist := [][]string{}
-	var output *iam.ListRolesOutput
-
-	output, err = client.ListRoles(context.TODO(), &iam.ListRolesInput{})
-	if err != nil {
-		sugar.Fatalf("ListRoles Error: %v", err)
-	}
-	var singlerole []string = []string{}
-
-	for _, object := range output.Roles {
-		sugar.Debugf("Role: %s", aws.ToString(object.RoleName))
-		singlerole = []string{aws.ToString(object.RoleName), aws.ToString(object.RoleId), aws.ToString(object.Arn), "IAM", "Role", ""}
-		rolelist = append(rolelist, singlerole)
-	}
-	iamrolelistchan <- rolelist
-	sugar.Infof("Finding all IAM roles function complete")
-
-}
-
-func findallcloudwatchlogsloggroups(client *cloudwatchlogs.Client, wg *sync.WaitGroup, loglistchan chan<- [][]string) {
-	defer close(loglistchan)
-	defer wg.Done()
-	// Initialise zap logging
-	logger, err := zap.NewProduction()
-	if err != nil {
-		log.Fatalf("can't initialize zap logger: %v", err)
-	}
-	defer logger.Sync()
-	sugar := logger.Sugar()
-	sugar.Infof("Finding all CloudWatch Logs log groups function running")
-	// Get the list of resources from the CloudWatchLogs API
-	loggrouplist := [][]string{}
-	var output *cloudwatchlogs.DescribeLogGroupsOutput
-
-	output, err = client.DescribeLogGroups(context.TODO(), &cloudwatchlogs.DescribeLogGroupsInput{})
-	if err != nil {
-		sugar.Fatalf("DescribeLogGroups Error: %v", err)
+// buildLogger constructs the single *zap.Logger every function in this
+// program logs through, at the requested level and format, with caller
+// info (file:line) attached so log output can be filtered in aggregators
+// like Loki/CloudWatch Insights.
+func buildLogger(level, format string) (*zap.Logger, error) {
+	var cfg zap.Config
+	switch format {
+	case "json":
+		cfg = zap.NewProductionConfig()
+	case "console":
+		cfg = zap.NewDevelopmentConfig()
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want json or console)", format)
 	}
-	var singlegroup []string = []string{}
-
-	for _, object := range output.LogGroups {
-		sugar.Debugf("LogGroup: %s", aws.ToString(object.LogGroupName))
-		singlegroup = []string{aws.ToString(object.LogGroupName), aws.ToString(object.Arn), "CloudWatchLogs", "LogGroup", ""}
-		loggrouplist = append(loggrouplist, singlegroup)
+	var lvl zapcore.Level
+	if err := lvl.Set(level); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", level, err)
 	}
-	loglistchan <- loggrouplist
-	sugar.Infof("Finding all CloudWatch Logs log groups function complete")
+	cfg.Level = zap.NewAtomicLevelAt(lvl)
+	return cfg.Build(zap.AddCaller())
 }
 
 func main() {
-	// Initialise zap logging
-	logger, err := zap.NewProduction()
-	if err != nil {
-		log.Fatalf("Can't initialize zap logger: %v", err)
-	}
-	defer logger.Sync()
-	sugar := logger.Sugar()
-
 	// flag section
 	var awsProfile string
 	var awsRegion string
 	var fileName string
 	var loadFileName string
+	var retryConfigFile string
+	var regionsFlag string
+	var profilesFlag string
+	var assumeRoleFlag string
+	var maxConcurrency int
+	var collectorsFlag string
+	var noProgress bool
+	var silent bool
+	var formatFlag string
+	var outputFlag string
+	var periodicFlag time.Duration
+	var retentionFlag time.Duration
+	var metricsAddr string
+	var logLevel string
+	var logFormat string
 	flag.StringVar(&awsProfile, "profile", "", "AWS profile to use")
 	flag.StringVar(&awsRegion, "region", "", "AWS region to use (Only required if profile without default region is specified)")
 	flag.StringVar(&loadFileName, "csvfile", "", "File to load taken from resource group")
+	flag.StringVar(&retryConfigFile, "retry-config", "", "YAML file of per-error-code retry policies (defaults to a built-in throttling policy)")
+	flag.StringVar(&regionsFlag, "regions", "", "Comma separated regions to scan, or \"all\" to resolve via ec2:DescribeRegions (defaults to -region)")
+	flag.StringVar(&profilesFlag, "profiles", "", "Comma separated AWS profiles to scan (defaults to -profile)")
+	flag.StringVar(&assumeRoleFlag, "assume-role", "", "Role ARN to assume on top of -profile for each account, e.g. for an AWS Organizations OrgReader role")
+	flag.IntVar(&maxConcurrency, "max-concurrency", 4, "Maximum number of (account, region) scans to run at once")
+	flag.StringVar(&collectorsFlag, "collectors", defaultCollectorNames, fmt.Sprintf("Comma separated collectors to run (available: %s)", strings.Join(RegisteredCollectorNames(), ", ")))
+	flag.BoolVar(&noProgress, "no-progress", false, "Disable the live progress bar (useful for CI logs)")
+	flag.BoolVar(&silent, "silent", false, "Disable the live progress bar (same effect as -no-progress)")
+	flag.StringVar(&formatFlag, "format", "csv", "Output format: csv, ndjson, yaml, or parquet")
+	flag.StringVar(&outputFlag, "output", "", "Output file, or \"-\" to stream to stdout; defaults to the trailing positional argument for backward compatibility. In --periodic mode, the directory timestamped reports and diffs are written to (default \".\")")
+	flag.DurationVar(&periodicFlag, "periodic", 0, "Run scans on this schedule instead of once, diffing each cycle against the last (e.g. 1h); 0 disables daemon mode")
+	flag.DurationVar(&retentionFlag, "retention", 24*time.Hour, "How long to keep in-memory snapshots in --periodic mode (written diff files are not pruned)")
+	flag.StringVar(&metricsAddr, "metrics-addr", ":9095", "Address to serve the Prometheus /metrics endpoint on in --periodic mode")
+	flag.StringVar(&logLevel, "log-level", "info", "Log level: debug, info, warn, or error")
+	flag.StringVar(&logFormat, "log-format", "json", "Log format: json or console")
 	help := flag.Bool("help", false, "Show help")
 
 	flag.Parse()
 
+	retriers, err := LoadRetryConfig(retryConfigFile)
+	if err != nil {
+		log.Fatalf("Failed loading retry config: %v", err)
+	}
+
+	collectors, err := ResolveCollectors(collectorsFlag)
+	if err != nil {
+		log.Fatalf("Failed resolving collectors: %v", err)
+	}
+
 	//print usage if no arguments are provided
 	if (len(os.Args) <= 1) || *help {
 		fmt.Print("Please use the following usage instructions while using this command:\n\nIf you have any question, reach out to innovature.ai \n\n")
@@ -269,192 +263,136 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Initialise the file name
-	if len(os.Args) >= 2 {
-		fileName = os.Args[len(os.Args)-1]
-	} else if fileName == "" && awsProfile != "" {
-		fileName = "output-resources.csv"
+	logger, err := buildLogger(logLevel, logFormat)
+	if err != nil {
+		log.Fatalf("Failed building logger: %v", err)
 	}
+	defer logger.Sync()
+	sugar := logger.Sugar()
 
 	if loadFileName == "" {
 		sugar.Fatalf("No CSV file specified")
 	}
 
-	// Initialise the file
-	csvFile, err := os.Create(fileName)
-	if err != nil {
-		sugar.Fatalf("Failed creating file %s: %s", fileName, err)
+	if awsProfile == "" && profilesFlag == "" && awsRegion == "" && regionsFlag == "" {
+		sugar.Fatalf("No profile or region specified")
 	}
-	csvwriter := csv.NewWriter(csvFile)
-	_ = csvwriter.Write([]string{"Sl.No.", "ARN/Resource ID", "LogicalID", "Name", "Service", "Type", "Region"})
+
+	// Root context for every AWS call and goroutine in the scan: cancelled
+	// on SIGINT/SIGTERM so an interrupted run stops promptly instead of
+	// losing its buffered CSV rows.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		sugar.Warnf("Received interrupt, cancelling in-flight scans")
+		cancel()
+	}()
+
+	// Resolve the base AWS config, used both as the default single scan
+	// target and as the credential source for resolving --regions all.
+	baseCfg, err := config.LoadDefaultConfig(ctx,
+		config.WithSharedConfigProfile(awsProfile), config.WithRegion(awsRegion))
 	if err != nil {
 		sugar.Fatal(err)
 	}
-	// Load the Shared AWS Configuration (~/.aws/config)
-	var cfg aws.Config
-	if awsProfile != "" {
-		sugar.Debugf("Using profile %s", awsProfile)
-		cfg, err = config.LoadDefaultConfig(context.TODO(),
-			config.WithSharedConfigProfile(awsProfile))
-		if err != nil {
-			sugar.Fatal(err)
-		}
-	} else if awsProfile != "" && awsRegion != "" {
-		sugar.Debugf("Using default cred chain")
-		cfg, err = config.LoadDefaultConfig(context.TODO(),
-			config.WithSharedConfigProfile(awsProfile), config.WithRegion(awsRegion))
-		if err != nil {
-			sugar.Fatal(err)
-		}
-	} else if awsRegion != "" {
-		sugar.Debugf("Using default cred chain")
-		cfg = aws.Config{
-			Region: awsRegion,
-		}
-	} else {
-		sugar.Fatalf("No profile or region specified")
-	}
-
-	// Create service clients
-	sugar.Infof("Starting service client creation")
-
-	cfnclient := cloudformation.NewFromConfig(cfg)
-	iamclient := iam.NewFromConfig(cfg)
-	logclient := cloudwatchlogs.NewFromConfig(cfg)
-
-	sugar.Infof("Service client creation complete")
-
-	stacklist := findallcloudformationstacks(cfnclient, "")
-
-	sugar.Infof("Obtained all CloudFormation stacks and resources")
-
-	loglistchan := make(chan [][]string, 1000000)
-	iamrolelistchan := make(chan [][]string, 1000000)
-	sugar.Infof("Initialised channels")
-
-	// Start the goroutines
-	var wg sync.WaitGroup
-	sugar.Infof("Starting goroutines")
-	wg.Add(2)
-	go findallcloudwatchlogsloggroups(logclient, &wg, loglistchan)
-	go findalliamroles(iamclient, &wg, iamrolelistchan)
-	sugar.Infof("Waiting for goroutines to finish")
-
-	wg.Wait()
-	iamrolelist := <-iamrolelistchan
-	loglist := <-loglistchan
-
-	sugar.Infof("All goroutines complete")
-
-	sugar.Infof("Converting channels to slices")
-
-	sugar.Infof("Converting channels to slices complete")
-	sugar.Debugf("Stacklist %v", stacklist)
-
-	// Cloudformation section
-
-	// CSV Load section
-	record := loadcsv(loadFileName)
-
-	sugar.Debugf("%v", record)
-
-	// Record Search section
 
-	_ = csvwriter.Write([]string{"a", fmt.Sprintf("Resource list from Cloudformation template")})
-	for countstack, stackId := range stacklist {
-		sugar.Debugf("Stack %s", stackId)
-
-		cfnsearchresult, countrecord := searchfromrecord(record, stackId)
-		if cfnsearchresult != nil {
-			_ = csvwriter.Write([]string{fmt.Sprint(countstack + 1), stackId, "", cfnsearchresult[1], cfnsearchresult[2], cfnsearchresult[3], cfnsearchresult[4]})
-
-			record = removesliceentry(record, countrecord)
-		} else {
-			sugar.Debugf("Not found %s", stackId)
-			_ = csvwriter.Write([]string{fmt.Sprint(countstack + 1), stackId, "", "", "", "", ""})
-		}
-		awscfnresourceslice := getallcfnresources(cfnclient, stackId)
-		sugar.Debugf("%v", awscfnresourceslice)
-		count := 0
-		for countcfn, resource := range awscfnresourceslice {
-
-			// check if the resource id exists in the csv record
-			searchresult, countrecord := searchfromrecord(record, resource.PhysicalResourceId)
-			if searchresult != nil {
-				sugar.Debugf("%v", searchresult)
-
-				record = removesliceentry(record, countrecord)
-				_ = csvwriter.Write([]string{fmt.Sprintf("%d.%d", countstack+1, count+1), resource.PhysicalResourceId, resource.LogicalResourceId, searchresult[1], searchresult[2], searchresult[3], searchresult[4]})
-				count++
-				// check if the resource id exists in the role list
-			} else if searchresult == nil {
-				iamsearchresult, iamcountrecord := searchfromrecord(iamrolelist, resource.PhysicalResourceId)
-				if iamsearchresult != nil {
-					sugar.Debugf("%v", iamsearchresult)
-
-					iamrolelist = removesliceentry(iamrolelist, iamcountrecord)
-					_ = csvwriter.Write([]string{fmt.Sprintf("%d.%d", countstack+1, count+1), resource.PhysicalResourceId, resource.LogicalResourceId, iamsearchresult[1], iamsearchresult[2], iamsearchresult[3], iamsearchresult[4]})
-					count++
-					// check if the resource id exists in the log group list
-				} else {
-					logsearchresult, logcountrecord := searchfromrecord(loglist, resource.PhysicalResourceId)
-					if logsearchresult != nil {
-						sugar.Debugf("%v", logsearchresult)
-
-						loglist = removesliceentry(loglist, logcountrecord)
-						_ = csvwriter.Write([]string{fmt.Sprintf("%d.%d", countstack+1, count+1), resource.PhysicalResourceId, resource.LogicalResourceId, logsearchresult[1], logsearchresult[2], logsearchresult[3], logsearchresult[4]})
-						count++
-					}
-				}
-				// if it doesn't exist in the csv record, then just directly print it without adding any additional information
-			} else {
-				_ = csvwriter.Write([]string{fmt.Sprintf("%d.%d", countstack+1, countcfn+1), resource.PhysicalResourceId, resource.LogicalResourceId, "", "", "", cfnsearchresult[4]})
-			}
+	regions, err := resolveRegions(ctx, baseCfg, regionsFlag)
+	if err != nil {
+		sugar.Fatalf("Failed resolving regions: %v", err)
+	}
+	accounts := resolveAccounts(profilesFlag, assumeRoleFlag)
 
-			sugar.Debugf("%s,%s\n", resource.PhysicalResourceId, resource.LogicalResourceId)
-			csvwriter.Flush()
-			if err != nil {
-				sugar.Fatal(err)
-			}
-		}
+	sugar.Infof("Scanning %d account(s) across %d region(s) with max-concurrency=%d", len(accounts), len(regions), maxConcurrency)
 
+	if periodicFlag > 0 {
+		runDaemon(ctx, periodicFlag, retentionFlag, metricsAddr, outputFlag, formatFlag, awsProfile, accounts, regions, maxConcurrency, retriers, loadFileName, collectors, sugar)
+		return
 	}
-	_ = csvwriter.Write([]string{"b", fmt.Sprintf("Non cloudformation linked resource list from CSV file")})
 
-	// add all the non cloudformation linked resources in the csv record to the csv file
+	// Initialise the file name
+	if len(os.Args) >= 2 {
+		fileName = os.Args[len(os.Args)-1]
+	} else if fileName == "" && awsProfile != "" {
+		fileName = "output-resources.csv"
+	}
+	if outputFlag != "" {
+		fileName = outputFlag
+	}
 
-	for countrecord, records := range record {
-		_ = csvwriter.Write([]string{fmt.Sprint(countrecord + 1), records[0], "", records[1], records[2], records[3], records[4], records[5]})
-		csvwriter.Flush()
+	// Initialise the output sink for the requested --format, streaming to
+	// stdout instead of a file when fileName is "-" so the tool composes
+	// with jq/yq in a pipeline.
+	var writer ReportWriter
+	if fileName == "-" {
+		writer, err = newReportWriter(formatFlag, os.Stdout, nil)
+	} else {
+		var outFile *os.File
+		outFile, err = os.Create(fileName)
 		if err != nil {
-			sugar.Fatal(err)
+			sugar.Fatalf("Failed creating file %s: %s", fileName, err)
 		}
+		writer, err = newReportWriter(formatFlag, outFile, outFile)
+	}
+	if err != nil {
+		sugar.Fatalf("Failed constructing %s output writer: %v", formatFlag, err)
+	}
+	if err := writer.WriteHeader(); err != nil {
+		sugar.Fatalf("Failed writing output header: %v", err)
 	}
 
-	_ = csvwriter.Write([]string{"c", fmt.Sprintf("Non cloudformation linked IAM roles")})
+	progress := NewProgress(!noProgress && !silent)
 
-	// add all the non cloudformation linked IAM roles to the csv file
+	scanFleet(ctx, awsProfile, accounts, regions, maxConcurrency, retriers, loadFileName, collectors, writer, progress, nil, sugar)
+	progress.Finish()
 
-	for countiamrecord, iamrecords := range iamrolelist {
-		_ = csvwriter.Write([]string{fmt.Sprint(countiamrecord + 1), iamrecords[0], iamrecords[1], iamrecords[2], iamrecords[3], iamrecords[4], iamrecords[5]})
-		csvwriter.Flush()
-		if err != nil {
-			sugar.Fatal(err)
-		}
+	if err := writer.Close(); err != nil {
+		sugar.Errorf("Failed closing %s output: %v", formatFlag, err)
 	}
 
-	_ = csvwriter.Write([]string{"d", fmt.Sprintf("Non cloudformation linked Cloudwatch logs")})
-
-	// add all the non cloudformation linked Cloudwatch logs to the csv file
-
-	for countlogrecord, logrecords := range loglist {
-		_ = csvwriter.Write([]string{fmt.Sprint(countlogrecord + 1), logrecords[0], logrecords[1], logrecords[2], logrecords[3], logrecords[4]})
-		csvwriter.Flush()
-		if err != nil {
-			sugar.Fatal(err)
-		}
+	if ctx.Err() != nil {
+		sugar.Warnf("Scan aborted after processing %d stack(s); partial results were written to %s", progress.Processed(), fileName)
+		os.Exit(130)
 	}
+}
 
-	csvFile.Close()
+// runDaemon drives --periodic mode: a Periodic ticks a full scanFleet pass
+// on a schedule, serving the accumulated counters at --metrics-addr/metrics
+// until ctx is cancelled.
+func runDaemon(ctx context.Context, period, retention time.Duration, metricsAddr, outputFlag, format, awsProfile string, accounts []accountProfile, regions []string, maxConcurrency int, retriers []Retrier, loadFileName string, collectors []Collector, sugar *zap.SugaredLogger) {
+	outDir := "."
+	if outputFlag != "" {
+		outDir = outputFlag
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		sugar.Fatalf("Failed creating --output directory %s: %v", outDir, err)
+	}
 
+	metrics := NewMetrics()
+	server := &http.Server{Addr: metricsAddr, Handler: metrics.Handler()}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			sugar.Errorf("Metrics server failed: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	sugar.Infof("Starting periodic scan every %s (retention=%s, metrics=%s)", period, retention, metricsAddr)
+
+	periodic := NewPeriodic(clockwork.NewRealClock(), period, retention, outDir, format, metrics)
+	periodic.Run(ctx, func(cycleCtx context.Context, w ReportWriter) error {
+		progress := NewProgress(false)
+		scanFleet(cycleCtx, awsProfile, accounts, regions, maxConcurrency, retriers, loadFileName, collectors, w, progress, metrics, sugar)
+		if cycleCtx.Err() != nil {
+			return cycleCtx.Err()
+		}
+		return nil
+	}, sugar)
 }
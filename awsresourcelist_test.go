@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"go.uber.org/zap"
+)
+
+// stackResourcesPage renders one ListStackResources response page of n
+// resources, truncated with nextToken unless it's empty. PhysicalResourceId
+// is a plain EC2 instance ID rather than a nested-stack ARN, so
+// getallcfnresources doesn't recurse and issue extra requests this fake
+// transport doesn't have pages for.
+func stackResourcesPage(n int, nextToken string) string {
+	var b strings.Builder
+	b.WriteString("<ListStackResourcesResponse><ListStackResourcesResult><StackResourceSummaries>")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "<member><LogicalResourceId>Resource%s%d</LogicalResourceId><PhysicalResourceId>i-%s%d</PhysicalResourceId><ResourceType>AWS::EC2::Instance</ResourceType><ResourceStatus>CREATE_COMPLETE</ResourceStatus><LastUpdatedTimestamp>2024-01-01T00:00:00Z</LastUpdatedTimestamp></member>", nextToken, i, nextToken, i)
+	}
+	b.WriteString("</StackResourceSummaries>")
+	if nextToken != "" {
+		fmt.Fprintf(&b, "<NextToken>%s</NextToken>", nextToken)
+	}
+	b.WriteString("</ListStackResourcesResult></ListStackResourcesResponse>")
+	return b.String()
+}
+
+func TestGetAllCFNResourcesPagination(t *testing.T) {
+	cases := []struct {
+		name     string
+		numPages int
+		perPage  int
+	}{
+		{"single page", 1, 10},
+		{"three pages", 3, 40},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pages := buildPages(tc.numPages, tc.perPage, stackResourcesPage)
+			cfg := testConfig(&pagedTransport{pages: pages, contentType: "text/xml"})
+			client := cloudformation.NewFromConfig(cfg)
+			sugar := zap.NewNop().Sugar()
+
+			resources := getallcfnresources(context.Background(), client, "arn:aws:cloudformation:us-east-1:123456789012:stack/test/abc", nil, nil, sugar)
+
+			want := tc.numPages * tc.perPage
+			if len(resources) != want {
+				t.Fatalf("got %d resources, want %d", len(resources), want)
+			}
+			if tc.numPages == 3 && len(resources) <= 100 {
+				t.Fatalf("expected >100 resources across 3 pages, got %d", len(resources))
+			}
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/apigateway"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+func init() {
+	Register(iamRoleCollector{})
+	Register(logGroupCollector{})
+	Register(s3BucketCollector{})
+	Register(lambdaFunctionCollector{})
+	Register(dynamoDBTableCollector{})
+	Register(rdsInstanceCollector{})
+	Register(ec2InstanceCollector{})
+	Register(ebsVolumeCollector{})
+	Register(securityGroupCollector{})
+	Register(snsTopicCollector{})
+	Register(sqsQueueCollector{})
+	Register(apiGatewayCollector{})
+}
+
+type iamRoleCollector struct{}
+
+func (iamRoleCollector) Name() string { return "iam" }
+
+func (iamRoleCollector) Collect(ctx context.Context, cfg aws.Config, retriers []Retrier) ([]Resource, error) {
+	client := iam.NewFromConfig(cfg)
+	resources := []Resource{}
+	paginator := iam.NewListRolesPaginator(client, &iam.ListRolesInput{})
+	for paginator.HasMorePages() {
+		var page *iam.ListRolesOutput
+		_, err := Do(ctx, retriers, func() error {
+			var callErr error
+			page, callErr = paginator.NextPage(ctx)
+			return callErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, role := range page.Roles {
+			resources = append(resources, Resource{
+				Name:    aws.ToString(role.RoleName),
+				ID:      aws.ToString(role.RoleId),
+				ARN:     aws.ToString(role.Arn),
+				Service: "IAM",
+				Type:    "Role",
+			})
+		}
+	}
+	return resources, nil
+}
+
+type logGroupCollector struct{}
+
+func (logGroupCollector) Name() string { return "logs" }
+
+func (logGroupCollector) Collect(ctx context.Context, cfg aws.Config, retriers []Retrier) ([]Resource, error) {
+	client := cloudwatchlogs.NewFromConfig(cfg)
+	resources := []Resource{}
+	paginator := cloudwatchlogs.NewDescribeLogGroupsPaginator(client, &cloudwatchlogs.DescribeLogGroupsInput{})
+	for paginator.HasMorePages() {
+		var page *cloudwatchlogs.DescribeLogGroupsOutput
+		_, err := Do(ctx, retriers, func() error {
+			var callErr error
+			page, callErr = paginator.NextPage(ctx)
+			return callErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range page.LogGroups {
+			resources = append(resources, Resource{
+				Name:    aws.ToString(group.LogGroupName),
+				ARN:     aws.ToString(group.Arn),
+				Service: "CloudWatchLogs",
+				Type:    "LogGroup",
+			})
+		}
+	}
+	return resources, nil
+}
+
+type s3BucketCollector struct{}
+
+func (s3BucketCollector) Name() string { return "s3" }
+
+func (s3BucketCollector) Collect(ctx context.Context, cfg aws.Config, retriers []Retrier) ([]Resource, error) {
+	client := s3.NewFromConfig(cfg)
+	resources := []Resource{}
+	paginator := s3.NewListBucketsPaginator(client, &s3.ListBucketsInput{})
+	for paginator.HasMorePages() {
+		var page *s3.ListBucketsOutput
+		_, err := Do(ctx, retriers, func() error {
+			var callErr error
+			page, callErr = paginator.NextPage(ctx)
+			return callErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, bucket := range page.Buckets {
+			name := aws.ToString(bucket.Name)
+			resources = append(resources, Resource{
+				Name:    name,
+				ARN:     "arn:aws:s3:::" + name,
+				Service: "S3",
+				Type:    "Bucket",
+			})
+		}
+	}
+	return resources, nil
+}
+
+type lambdaFunctionCollector struct{}
+
+func (lambdaFunctionCollector) Name() string { return "lambda" }
+
+func (lambdaFunctionCollector) Collect(ctx context.Context, cfg aws.Config, retriers []Retrier) ([]Resource, error) {
+	client := lambda.NewFromConfig(cfg)
+	resources := []Resource{}
+	paginator := lambda.NewListFunctionsPaginator(client, &lambda.ListFunctionsInput{})
+	for paginator.HasMorePages() {
+		var page *lambda.ListFunctionsOutput
+		_, err := Do(ctx, retriers, func() error {
+			var callErr error
+			page, callErr = paginator.NextPage(ctx)
+			return callErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, fn := range page.Functions {
+			resources = append(resources, Resource{
+				Name:    aws.ToString(fn.FunctionName),
+				ARN:     aws.ToString(fn.FunctionArn),
+				Service: "Lambda",
+				Type:    "Function",
+				Region:  cfg.Region,
+			})
+		}
+	}
+	return resources, nil
+}
+
+type dynamoDBTableCollector struct{}
+
+func (dynamoDBTableCollector) Name() string { return "dynamodb" }
+
+func (dynamoDBTableCollector) Collect(ctx context.Context, cfg aws.Config, retriers []Retrier) ([]Resource, error) {
+	client := dynamodb.NewFromConfig(cfg)
+	resources := []Resource{}
+	paginator := dynamodb.NewListTablesPaginator(client, &dynamodb.ListTablesInput{})
+	for paginator.HasMorePages() {
+		var page *dynamodb.ListTablesOutput
+		_, err := Do(ctx, retriers, func() error {
+			var callErr error
+			page, callErr = paginator.NextPage(ctx)
+			return callErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range page.TableNames {
+			resources = append(resources, Resource{
+				Name:    name,
+				Service: "DynamoDB",
+				Type:    "Table",
+				Region:  cfg.Region,
+			})
+		}
+	}
+	return resources, nil
+}
+
+type rdsInstanceCollector struct{}
+
+func (rdsInstanceCollector) Name() string { return "rds" }
+
+func (rdsInstanceCollector) Collect(ctx context.Context, cfg aws.Config, retriers []Retrier) ([]Resource, error) {
+	client := rds.NewFromConfig(cfg)
+	resources := []Resource{}
+	paginator := rds.NewDescribeDBInstancesPaginator(client, &rds.DescribeDBInstancesInput{})
+	for paginator.HasMorePages() {
+		var page *rds.DescribeDBInstancesOutput
+		_, err := Do(ctx, retriers, func() error {
+			var callErr error
+			page, callErr = paginator.NextPage(ctx)
+			return callErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range page.DBInstances {
+			resources = append(resources, Resource{
+				Name:    aws.ToString(instance.DBInstanceIdentifier),
+				ARN:     aws.ToString(instance.DBInstanceArn),
+				Service: "RDS",
+				Type:    "DBInstance",
+				Region:  cfg.Region,
+			})
+		}
+	}
+	return resources, nil
+}
+
+type ec2InstanceCollector struct{}
+
+func (ec2InstanceCollector) Name() string { return "ec2" }
+
+func (ec2InstanceCollector) Collect(ctx context.Context, cfg aws.Config, retriers []Retrier) ([]Resource, error) {
+	client := ec2.NewFromConfig(cfg)
+	resources := []Resource{}
+	paginator := ec2.NewDescribeInstancesPaginator(client, &ec2.DescribeInstancesInput{})
+	for paginator.HasMorePages() {
+		var page *ec2.DescribeInstancesOutput
+		_, err := Do(ctx, retriers, func() error {
+			var callErr error
+			page, callErr = paginator.NextPage(ctx)
+			return callErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				resources = append(resources, Resource{
+					Name:    aws.ToString(instance.InstanceId),
+					ID:      aws.ToString(instance.InstanceId),
+					Service: "EC2",
+					Type:    "Instance",
+					Region:  cfg.Region,
+				})
+			}
+		}
+	}
+	return resources, nil
+}
+
+type ebsVolumeCollector struct{}
+
+func (ebsVolumeCollector) Name() string { return "ebs" }
+
+func (ebsVolumeCollector) Collect(ctx context.Context, cfg aws.Config, retriers []Retrier) ([]Resource, error) {
+	client := ec2.NewFromConfig(cfg)
+	resources := []Resource{}
+	paginator := ec2.NewDescribeVolumesPaginator(client, &ec2.DescribeVolumesInput{})
+	for paginator.HasMorePages() {
+		var page *ec2.DescribeVolumesOutput
+		_, err := Do(ctx, retriers, func() error {
+			var callErr error
+			page, callErr = paginator.NextPage(ctx)
+			return callErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, volume := range page.Volumes {
+			resources = append(resources, Resource{
+				Name:    aws.ToString(volume.VolumeId),
+				ID:      aws.ToString(volume.VolumeId),
+				Service: "EC2",
+				Type:    "Volume",
+				Region:  cfg.Region,
+			})
+		}
+	}
+	return resources, nil
+}
+
+type securityGroupCollector struct{}
+
+func (securityGroupCollector) Name() string { return "security-groups" }
+
+func (securityGroupCollector) Collect(ctx context.Context, cfg aws.Config, retriers []Retrier) ([]Resource, error) {
+	client := ec2.NewFromConfig(cfg)
+	resources := []Resource{}
+	paginator := ec2.NewDescribeSecurityGroupsPaginator(client, &ec2.DescribeSecurityGroupsInput{})
+	for paginator.HasMorePages() {
+		var page *ec2.DescribeSecurityGroupsOutput
+		_, err := Do(ctx, retriers, func() error {
+			var callErr error
+			page, callErr = paginator.NextPage(ctx)
+			return callErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, sg := range page.SecurityGroups {
+			resources = append(resources, Resource{
+				Name:    aws.ToString(sg.GroupName),
+				ID:      aws.ToString(sg.GroupId),
+				Service: "EC2",
+				Type:    "SecurityGroup",
+				Region:  cfg.Region,
+			})
+		}
+	}
+	return resources, nil
+}
+
+type snsTopicCollector struct{}
+
+func (snsTopicCollector) Name() string { return "sns" }
+
+func (snsTopicCollector) Collect(ctx context.Context, cfg aws.Config, retriers []Retrier) ([]Resource, error) {
+	client := sns.NewFromConfig(cfg)
+	resources := []Resource{}
+	paginator := sns.NewListTopicsPaginator(client, &sns.ListTopicsInput{})
+	for paginator.HasMorePages() {
+		var page *sns.ListTopicsOutput
+		_, err := Do(ctx, retriers, func() error {
+			var callErr error
+			page, callErr = paginator.NextPage(ctx)
+			return callErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, topic := range page.Topics {
+			arn := aws.ToString(topic.TopicArn)
+			resources = append(resources, Resource{
+				Name:    arn,
+				ARN:     arn,
+				Service: "SNS",
+				Type:    "Topic",
+				Region:  cfg.Region,
+			})
+		}
+	}
+	return resources, nil
+}
+
+type sqsQueueCollector struct{}
+
+func (sqsQueueCollector) Name() string { return "sqs" }
+
+func (sqsQueueCollector) Collect(ctx context.Context, cfg aws.Config, retriers []Retrier) ([]Resource, error) {
+	client := sqs.NewFromConfig(cfg)
+	resources := []Resource{}
+	// MaxResults must be set for SQS to hand back a NextToken at all.
+	paginator := sqs.NewListQueuesPaginator(client, &sqs.ListQueuesInput{MaxResults: aws.Int32(1000)})
+	for paginator.HasMorePages() {
+		var page *sqs.ListQueuesOutput
+		_, err := Do(ctx, retriers, func() error {
+			var callErr error
+			page, callErr = paginator.NextPage(ctx)
+			return callErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, url := range page.QueueUrls {
+			resources = append(resources, Resource{
+				Name:    url,
+				Service: "SQS",
+				Type:    "Queue",
+				Region:  cfg.Region,
+			})
+		}
+	}
+	return resources, nil
+}
+
+type apiGatewayCollector struct{}
+
+func (apiGatewayCollector) Name() string { return "apigateway" }
+
+func (apiGatewayCollector) Collect(ctx context.Context, cfg aws.Config, retriers []Retrier) ([]Resource, error) {
+	client := apigateway.NewFromConfig(cfg)
+	resources := []Resource{}
+	paginator := apigateway.NewGetRestApisPaginator(client, &apigateway.GetRestApisInput{})
+	for paginator.HasMorePages() {
+		var page *apigateway.GetRestApisOutput
+		_, err := Do(ctx, retriers, func() error {
+			var callErr error
+			page, callErr = paginator.NextPage(ctx)
+			return callErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, api := range page.Items {
+			resources = append(resources, Resource{
+				Name:    aws.ToString(api.Name),
+				ID:      aws.ToString(api.Id),
+				Service: "APIGateway",
+				Type:    "RestApi",
+				Region:  cfg.Region,
+			})
+		}
+	}
+	return resources, nil
+}
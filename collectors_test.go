@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// pagedTransport is a fake http.RoundTripper that hands back pages in order,
+// one per request, so a Collector's paginator can be exercised without a
+// real AWS endpoint.
+type pagedTransport struct {
+	pages       []string
+	contentType string
+	next        int
+}
+
+func (p *pagedTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	if p.next >= len(p.pages) {
+		return nil, fmt.Errorf("unexpected request: all %d pages already served", len(p.pages))
+	}
+	body := p.pages[p.next]
+	p.next++
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{p.contentType}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}, nil
+}
+
+// testConfig returns an aws.Config that routes every request through rt
+// instead of the network, with credentials that skip SigV4 signing.
+func testConfig(rt http.RoundTripper) aws.Config {
+	return aws.Config{
+		Region:      "us-east-1",
+		Credentials: aws.AnonymousCredentials{},
+		HTTPClient:  &http.Client{Transport: rt},
+	}
+}
+
+// iamRolesPage renders one ListRoles response page of n roles, truncated
+// with marker unless marker is empty.
+func iamRolesPage(n int, marker string) string {
+	var b strings.Builder
+	b.WriteString("<ListRolesResponse><ListRolesResult><Roles>")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "<member><RoleName>role-%s-%d</RoleName><RoleId>AROA%s%d</RoleId><Arn>arn:aws:iam::123456789012:role/role-%s-%d</Arn><Path>/</Path><CreateDate>2024-01-01T00:00:00Z</CreateDate></member>", marker, i, marker, i, marker, i)
+	}
+	b.WriteString("</Roles>")
+	if marker != "" {
+		fmt.Fprintf(&b, "<IsTruncated>true</IsTruncated><Marker>%s</Marker>", marker)
+	} else {
+		b.WriteString("<IsTruncated>false</IsTruncated>")
+	}
+	b.WriteString("</ListRolesResult></ListRolesResponse>")
+	return b.String()
+}
+
+// logGroupsPage renders one DescribeLogGroups response page of n log
+// groups, with nextToken unless it's empty.
+func logGroupsPage(n int, nextToken string) string {
+	var b strings.Builder
+	b.WriteString(`{"logGroups":[`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"logGroupName":"group-%s-%d","arn":"arn:aws:logs:us-east-1:123456789012:log-group:group-%s-%d"}`, nextToken, i, nextToken, i)
+	}
+	b.WriteString("]")
+	if nextToken != "" {
+		fmt.Fprintf(&b, `,"nextToken":%q`, nextToken)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// buildPages renders numPages pages of perPage items each via render,
+// threading a "page2", "page3", ... marker into every page but the last.
+func buildPages(numPages, perPage int, render func(n int, marker string) string) []string {
+	pages := make([]string, numPages)
+	for i := 0; i < numPages; i++ {
+		marker := ""
+		if i < numPages-1 {
+			marker = fmt.Sprintf("page%d", i+2)
+		}
+		pages[i] = render(perPage, marker)
+	}
+	return pages
+}
+
+func TestIAMRoleCollectorPagination(t *testing.T) {
+	cases := []struct {
+		name     string
+		numPages int
+		perPage  int
+	}{
+		{"single page", 1, 10},
+		{"three pages", 3, 40},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pages := buildPages(tc.numPages, tc.perPage, iamRolesPage)
+			cfg := testConfig(&pagedTransport{pages: pages, contentType: "text/xml"})
+			resources, err := (iamRoleCollector{}).Collect(context.Background(), cfg, nil)
+			if err != nil {
+				t.Fatalf("Collect: %v", err)
+			}
+			want := tc.numPages * tc.perPage
+			if len(resources) != want {
+				t.Fatalf("got %d resources, want %d", len(resources), want)
+			}
+			if tc.numPages == 3 && len(resources) <= 100 {
+				t.Fatalf("expected >100 resources across 3 pages, got %d", len(resources))
+			}
+		})
+	}
+}
+
+func TestLogGroupCollectorPagination(t *testing.T) {
+	cases := []struct {
+		name     string
+		numPages int
+		perPage  int
+	}{
+		{"single page", 1, 10},
+		{"three pages", 3, 40},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pages := buildPages(tc.numPages, tc.perPage, logGroupsPage)
+			cfg := testConfig(&pagedTransport{pages: pages, contentType: "application/x-amz-json-1.1"})
+			resources, err := (logGroupCollector{}).Collect(context.Background(), cfg, nil)
+			if err != nil {
+				t.Fatalf("Collect: %v", err)
+			}
+			want := tc.numPages * tc.perPage
+			if len(resources) != want {
+				t.Fatalf("got %d resources, want %d", len(resources), want)
+			}
+			if tc.numPages == 3 && len(resources) <= 100 {
+				t.Fatalf("expected >100 resources across 3 pages, got %d", len(resources))
+			}
+		})
+	}
+}
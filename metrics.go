@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Metrics accumulates counters and the latest-snapshot gauges across
+// --periodic scan cycles for the Prometheus text-format /metrics endpoint
+// the daemon exposes.
+type Metrics struct {
+	mu                sync.Mutex
+	scanDurationCount uint64
+	scanDurationSum   float64
+	resources         map[string]int64
+	scanErrorsTotal   map[string]int64
+}
+
+// NewMetrics returns an empty Metrics ready to be scraped.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		resources:       map[string]int64{},
+		scanErrorsTotal: map[string]int64{},
+	}
+}
+
+// ObserveScanDuration records one completed scan cycle's wall time as a
+// running count+sum, the same O(1)-per-cycle shape as a Prometheus summary,
+// instead of keeping every cycle's duration forever.
+func (m *Metrics) ObserveScanDuration(seconds float64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scanDurationCount++
+	m.scanDurationSum += seconds
+}
+
+// SetResources replaces the per-service resource gauge with counts, the
+// latest scan cycle's snapshot. Unlike a counter, a service absent from
+// counts (nothing found, or the collector was dropped) stops being
+// reported rather than keeping its last value forever.
+func (m *Metrics) SetResources(counts map[string]int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resources = counts
+}
+
+// AddScanError increments the counter for one AWS error code, mirroring the
+// codes retry.go matches retriers against. A nil Metrics (the one-shot,
+// non-periodic scan path, which doesn't serve /metrics) is a no-op, the same
+// as a disabled Progress.
+func (m *Metrics) AddScanError(code string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scanErrorsTotal[code]++
+}
+
+// Handler renders the accumulated counters in Prometheus text exposition
+// format for net/http to serve at /metrics.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		fmt.Fprintln(w, "# HELP awsresourcelist_scan_duration_seconds Summary of completed scan cycle durations.")
+		fmt.Fprintln(w, "# TYPE awsresourcelist_scan_duration_seconds summary")
+		fmt.Fprintf(w, "awsresourcelist_scan_duration_seconds_sum %f\n", m.scanDurationSum)
+		fmt.Fprintf(w, "awsresourcelist_scan_duration_seconds_count %d\n", m.scanDurationCount)
+
+		fmt.Fprintln(w, "# HELP awsresourcelist_resources Resources found in the latest scan cycle, by service.")
+		fmt.Fprintln(w, "# TYPE awsresourcelist_resources gauge")
+		for _, service := range sortedKeys(m.resources) {
+			fmt.Fprintf(w, "awsresourcelist_resources{service=%q} %d\n", service, m.resources[service])
+		}
+
+		fmt.Fprintln(w, "# HELP awsresourcelist_scan_errors_total Scan errors, by AWS error code.")
+		fmt.Fprintln(w, "# TYPE awsresourcelist_scan_errors_total counter")
+		for _, code := range sortedKeys(m.scanErrorsTotal) {
+			fmt.Fprintf(w, "awsresourcelist_scan_errors_total{code=%q} %d\n", code, m.scanErrorsTotal[code])
+		}
+	})
+}
+
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
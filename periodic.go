@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"go.uber.org/zap"
+)
+
+// Snapshot is one completed scan cycle's resources, keyed by ARN/physical
+// resource ID, so consecutive cycles can be diffed against each other.
+type Snapshot struct {
+	Taken     time.Time
+	Resources map[string]reportRow
+}
+
+// SnapshotDiff is what changed between two consecutive Snapshots.
+type SnapshotDiff struct {
+	Taken   time.Time   `json:"taken"`
+	Added   []reportRow `json:"added"`
+	Removed []reportRow `json:"removed"`
+	Changed []reportRow `json:"changed"`
+}
+
+// diffSnapshots computes what changed between prev and cur. A resource is
+// "changed" when its ID is present in both but any other column differs.
+func diffSnapshots(prev, cur Snapshot) SnapshotDiff {
+	d := SnapshotDiff{Taken: cur.Taken}
+	for id, row := range cur.Resources {
+		prevRow, ok := prev.Resources[id]
+		if !ok {
+			d.Added = append(d.Added, row)
+			continue
+		}
+		if prevRow != row {
+			d.Changed = append(d.Changed, row)
+		}
+	}
+	for id, row := range prev.Resources {
+		if _, ok := cur.Resources[id]; !ok {
+			d.Removed = append(d.Removed, row)
+		}
+	}
+	return d
+}
+
+// snapshotCollector wraps a ReportWriter, also recording every row it sees
+// into an in-memory map keyed by ARN/physical resource ID, so Periodic can
+// turn one scan cycle into a Snapshot without changing how runScan writes.
+type snapshotCollector struct {
+	ReportWriter
+	mu        sync.Mutex
+	resources map[string]reportRow
+}
+
+func newSnapshotCollector(w ReportWriter) *snapshotCollector {
+	return &snapshotCollector{ReportWriter: w, resources: map[string]reportRow{}}
+}
+
+func (s *snapshotCollector) WriteStackResource(stack, resource string, enrichment []string) error {
+	row := newReportRow(stack, resource, enrichment)
+	s.mu.Lock()
+	s.resources[row.ResourceID] = row
+	s.mu.Unlock()
+	return s.ReportWriter.WriteStackResource(stack, resource, enrichment)
+}
+
+func (s *snapshotCollector) WriteOrphan(section string, row []string) error {
+	r := rowFromFlat(row)
+	s.mu.Lock()
+	s.resources[r.ResourceID] = r
+	s.mu.Unlock()
+	return s.ReportWriter.WriteOrphan(section, row)
+}
+
+// Snapshot returns a point-in-time copy of every row recorded so far.
+func (s *snapshotCollector) Snapshot(taken time.Time) Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resources := make(map[string]reportRow, len(s.resources))
+	for id, row := range s.resources {
+		resources[id] = row
+	}
+	return Snapshot{Taken: taken, Resources: resources}
+}
+
+// Periodic runs the scan pipeline on a schedule instead of once, modeled on
+// the periodic-compaction loop in etcd's mvcc package: a clockwork.Clock so
+// tests can drive cycles deterministically, and a slice of recent Snapshots
+// so each cycle diffs against the last one. Snapshots older than retention
+// get pruned; with retention <= 0 none are pruned.
+type Periodic struct {
+	clock     clockwork.Clock
+	period    time.Duration
+	retention time.Duration
+	outDir    string
+	format    string
+	metrics   *Metrics
+
+	revs []Snapshot
+}
+
+// NewPeriodic builds a Periodic that writes timestamped output into outDir
+// every period, keeping snapshots for retention before pruning them.
+func NewPeriodic(clock clockwork.Clock, period, retention time.Duration, outDir, format string, metrics *Metrics) *Periodic {
+	return &Periodic{
+		clock:     clock,
+		period:    period,
+		retention: retention,
+		outDir:    outDir,
+		format:    format,
+		metrics:   metrics,
+	}
+}
+
+// scanCycle performs one full fleet scan, writing every row through w, and
+// returns any error that should discard the cycle. tick supplies w, writes
+// the timestamped report and diff file, and takes the Snapshot.
+type scanCycle func(ctx context.Context, w ReportWriter) error
+
+// Run ticks every p.period until ctx is cancelled, running one scanCycle
+// per tick and diffing it against the previous cycle.
+func (p *Periodic) Run(ctx context.Context, scan scanCycle, sugar *zap.SugaredLogger) {
+	ticker := p.clock.NewTicker(p.period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.Chan():
+			p.tick(ctx, scan, sugar)
+		}
+	}
+}
+
+func (p *Periodic) tick(ctx context.Context, scan scanCycle, sugar *zap.SugaredLogger) {
+	taken := p.clock.Now()
+	reportPath := filepath.Join(p.outDir, fmt.Sprintf("scan-%d.%s", taken.Unix(), reportExtension(p.format)))
+
+	reportFile, err := os.Create(reportPath)
+	if err != nil {
+		sugar.Errorf("Periodic scan: failed creating %s: %v", reportPath, err)
+		return
+	}
+	writer, err := newReportWriter(p.format, reportFile, reportFile)
+	if err != nil {
+		sugar.Errorf("Periodic scan: failed constructing %s writer: %v", p.format, err)
+		reportFile.Close()
+		return
+	}
+	collector := newSnapshotCollector(writer)
+	if err := collector.WriteHeader(); err != nil {
+		sugar.Errorf("Periodic scan: failed writing header: %v", err)
+	}
+
+	start := p.clock.Now()
+	scanErr := scan(ctx, collector)
+	p.metrics.ObserveScanDuration(p.clock.Now().Sub(start).Seconds())
+
+	if err := collector.Close(); err != nil {
+		sugar.Errorf("Periodic scan: failed closing %s: %v", reportPath, err)
+	}
+
+	if scanErr != nil {
+		sugar.Errorf("Periodic scan cycle failed: %v", scanErr)
+		return
+	}
+
+	snap := collector.Snapshot(taken)
+	counts := map[string]int64{}
+	for _, row := range snap.Resources {
+		counts[row.Service]++
+	}
+	p.metrics.SetResources(counts)
+
+	if len(p.revs) > 0 {
+		p.writeDiff(diffSnapshots(p.revs[len(p.revs)-1], snap), sugar)
+	}
+
+	p.revs = append(p.revs, snap)
+	p.prune()
+}
+
+func (p *Periodic) writeDiff(d SnapshotDiff, sugar *zap.SugaredLogger) {
+	path := filepath.Join(p.outDir, fmt.Sprintf("diff-%d.json", d.Taken.Unix()))
+	f, err := os.Create(path)
+	if err != nil {
+		sugar.Errorf("Failed creating diff file %s: %v", path, err)
+		return
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(d); err != nil {
+		sugar.Errorf("Failed writing diff file %s: %v", path, err)
+	}
+}
+
+// prune drops snapshots older than p.retention, always keeping at least the
+// most recent one so the next tick has something to diff against.
+func (p *Periodic) prune() {
+	if p.retention <= 0 || len(p.revs) <= 1 {
+		return
+	}
+	cutoff := p.clock.Now().Add(-p.retention)
+	kept := p.revs[:0]
+	for _, r := range p.revs {
+		if r.Taken.After(cutoff) {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) == 0 {
+		kept = p.revs[len(p.revs)-1:]
+	}
+	p.revs = kept
+}
+
+// reportExtension picks the file extension a timestamped report filename
+// should use for format.
+func reportExtension(format string) string {
+	switch format {
+	case "ndjson":
+		return "ndjson"
+	case "yaml":
+		return "yaml"
+	case "parquet":
+		return "parquet"
+	default:
+		return "csv"
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sync/atomic"
+
+	"github.com/cheggaaa/pb/v3"
+)
+
+// Progress tracks total-stacks-discovered vs. stacks-processed across every
+// Scanner in a run. It's backed by a single cheggaaa/pb bar so a
+// multi-region fan-out still shows one meaningful number instead of one bar
+// per goroutine.
+type Progress struct {
+	bar     *pb.ProgressBar
+	total   int64
+	current int64
+}
+
+// NewProgress returns a live progress bar, or a no-op Progress when enabled
+// is false (--no-progress / --silent).
+func NewProgress(enabled bool) *Progress {
+	if !enabled {
+		return &Progress{}
+	}
+	bar := pb.New64(0)
+	bar.SetMaxWidth(78)
+	bar.Start()
+	return &Progress{bar: bar}
+}
+
+// AddStacks records n more stacks discovered by a Scanner.
+func (p *Progress) AddStacks(n int) {
+	if p == nil || p.bar == nil {
+		return
+	}
+	total := atomic.AddInt64(&p.total, int64(n))
+	p.bar.SetTotal(total)
+}
+
+// StackDone marks one stack as fully processed.
+func (p *Progress) StackDone() {
+	if p == nil {
+		return
+	}
+	atomic.AddInt64(&p.current, 1)
+	if p.bar == nil {
+		return
+	}
+	p.bar.Increment()
+}
+
+// Processed reports how many stacks have been marked done so far, for the
+// abort summary.
+func (p *Progress) Processed() int64 {
+	if p == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&p.current)
+}
+
+// Finish stops the bar, if one is running.
+func (p *Progress) Finish() {
+	if p == nil || p.bar == nil {
+		return
+	}
+	p.bar.Finish()
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/segmentio/parquet-go"
+	"gopkg.in/yaml.v3"
+)
+
+// reportRow is the flat ten-column shape the tool has always emitted as a
+// CSV row. Every ReportWriter renders it, or a tree built from it, in its
+// own format.
+type reportRow struct {
+	Index      string `json:"index" yaml:"index"`
+	ResourceID string `json:"resourceId" yaml:"resourceId"`
+	LogicalID  string `json:"logicalId,omitempty" yaml:"logicalId,omitempty"`
+	Name       string `json:"name,omitempty" yaml:"name,omitempty"`
+	Service    string `json:"service,omitempty" yaml:"service,omitempty"`
+	Type       string `json:"type,omitempty" yaml:"type,omitempty"`
+	Region     string `json:"region,omitempty" yaml:"region,omitempty"`
+	Attempts   string `json:"attempts,omitempty" yaml:"attempts,omitempty"`
+	Account    string `json:"account,omitempty" yaml:"account,omitempty"`
+	ScanRegion string `json:"scanRegion,omitempty" yaml:"scanRegion,omitempty"`
+}
+
+// flat renders r back into the ten-column form the CSV writer has always
+// used, so the CSV ReportWriter can stay byte-for-byte compatible.
+func (r reportRow) flat() []string {
+	return []string{r.Index, r.ResourceID, r.LogicalID, r.Name, r.Service, r.Type, r.Region, r.Attempts, r.Account, r.ScanRegion}
+}
+
+// newReportRow builds a reportRow from a WriteStackResource call: stack is
+// the Sl.No.-style index, resource the ARN/physical ID, and enrichment the
+// remaining LogicalID, Name, Service, Type, Region, Attempts, Account, and
+// ScanRegion columns, in that order.
+func newReportRow(stack, resource string, enrichment []string) reportRow {
+	row := reportRow{Index: stack, ResourceID: resource}
+	fields := []*string{&row.LogicalID, &row.Name, &row.Service, &row.Type, &row.Region, &row.Attempts, &row.Account, &row.ScanRegion}
+	for i, f := range fields {
+		if i < len(enrichment) {
+			*f = enrichment[i]
+		}
+	}
+	return row
+}
+
+// rowFromFlat is the inverse of flat, for WriteOrphan callers that still
+// build their row as a plain ten-column slice.
+func rowFromFlat(row []string) reportRow {
+	get := func(i int) string {
+		if i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+	return reportRow{
+		Index: get(0), ResourceID: get(1), LogicalID: get(2), Name: get(3),
+		Service: get(4), Type: get(5), Region: get(6), Attempts: get(7),
+		Account: get(8), ScanRegion: get(9),
+	}
+}
+
+// ReportWriter is the output sink runScan writes through, so the
+// CloudFormation-vs-collector bucketing logic renders as CSV, NDJSON, YAML,
+// or Parquet without runScan knowing which. Implementations must be safe
+// for concurrent use, since one ReportWriter is shared across every
+// (account, region) Scanner goroutine.
+type ReportWriter interface {
+	// WriteHeader is called once before any rows, to emit column headers
+	// (CSV) or otherwise prepare the sink.
+	WriteHeader() error
+	// WriteStackResource writes one CloudFormation stack or stack-resource
+	// row.
+	WriteStackResource(stack, resource string, enrichment []string) error
+	// WriteOrphan writes one resource that wasn't matched to any
+	// CloudFormation stack, grouped under section (e.g. "csv", the loaded
+	// CSV's own records, or a collector name).
+	WriteOrphan(section string, row []string) error
+	Close() error
+}
+
+// newReportWriter builds the ReportWriter for format, writing to w and
+// closing closer (if non-nil) on Close.
+func newReportWriter(format string, w io.Writer, closer io.Closer) (ReportWriter, error) {
+	switch format {
+	case "", "csv":
+		return newCSVReportWriter(w, closer), nil
+	case "ndjson":
+		return newNDJSONReportWriter(w, closer), nil
+	case "yaml":
+		return newYAMLReportWriter(w, closer), nil
+	case "parquet":
+		return newParquetReportWriter(w, closer), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (want csv, ndjson, yaml, or parquet)", format)
+	}
+}
+
+// csvReportWriter preserves the tool's original flat-CSV output. Each
+// orphan section gets a lead-in marker row the first time it's seen,
+// instead of the fixed "a"/"b"/"c" markers runScan used to write itself.
+type csvReportWriter struct {
+	mu          sync.Mutex
+	w           *csv.Writer
+	closer      io.Closer
+	seenSection map[string]bool
+}
+
+func newCSVReportWriter(w io.Writer, closer io.Closer) *csvReportWriter {
+	return &csvReportWriter{w: csv.NewWriter(w), closer: closer, seenSection: map[string]bool{}}
+}
+
+func (c *csvReportWriter) write(row []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.w.Write(row); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvReportWriter) WriteHeader() error {
+	return c.write([]string{"Sl.No.", "ARN/Resource ID", "LogicalID", "Name", "Service", "Type", "Region", "Attempts", "Account", "ScanRegion"})
+}
+
+func (c *csvReportWriter) WriteStackResource(stack, resource string, enrichment []string) error {
+	return c.write(newReportRow(stack, resource, enrichment).flat())
+}
+
+func (c *csvReportWriter) WriteOrphan(section string, row []string) error {
+	c.mu.Lock()
+	first := !c.seenSection[section]
+	c.seenSection[section] = true
+	c.mu.Unlock()
+	if first {
+		if err := c.write([]string{"#" + section, fmt.Sprintf("Resources not matched to a CloudFormation stack (%s)", section)}); err != nil {
+			return err
+		}
+	}
+	return c.write(row)
+}
+
+func (c *csvReportWriter) Close() error {
+	if c.closer != nil {
+		return c.closer.Close()
+	}
+	return nil
+}
+
+// ndjsonReportWriter streams one JSON object per line, so the output
+// composes with jq in a pipeline without buffering the whole scan.
+type ndjsonReportWriter struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	closer io.Closer
+	enc    *json.Encoder
+}
+
+func newNDJSONReportWriter(w io.Writer, closer io.Closer) *ndjsonReportWriter {
+	bw := bufio.NewWriter(w)
+	return &ndjsonReportWriter{w: bw, closer: closer, enc: json.NewEncoder(bw)}
+}
+
+func (n *ndjsonReportWriter) WriteHeader() error { return nil }
+
+func (n *ndjsonReportWriter) WriteStackResource(stack, resource string, enrichment []string) error {
+	type line struct {
+		Kind string `json:"kind"`
+		reportRow
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err := n.enc.Encode(line{Kind: "stack", reportRow: newReportRow(stack, resource, enrichment)}); err != nil {
+		return err
+	}
+	return n.w.Flush()
+}
+
+func (n *ndjsonReportWriter) WriteOrphan(section string, row []string) error {
+	type line struct {
+		Kind    string `json:"kind"`
+		Section string `json:"section"`
+		reportRow
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err := n.enc.Encode(line{Kind: "orphan", Section: section, reportRow: rowFromFlat(row)}); err != nil {
+		return err
+	}
+	return n.w.Flush()
+}
+
+func (n *ndjsonReportWriter) Close() error {
+	if n.closer != nil {
+		return n.closer.Close()
+	}
+	return nil
+}
+
+// yamlReportWriter buffers every row in memory and renders it as a single
+// document on Close, with a top-level stacks list and an orphans map keyed
+// by section. Stack and stack-resource rows are listed flat under stacks, in
+// the order written; a resource's parent stack shows only through its Index
+// (e.g. "1.2" under "1"), and nested stacks are not rendered as a tree.
+type yamlReportWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	closer  io.Closer
+	stacks  []reportRow
+	orphans map[string][]reportRow
+}
+
+func newYAMLReportWriter(w io.Writer, closer io.Closer) *yamlReportWriter {
+	return &yamlReportWriter{w: w, closer: closer, orphans: map[string][]reportRow{}}
+}
+
+func (y *yamlReportWriter) WriteHeader() error { return nil }
+
+func (y *yamlReportWriter) WriteStackResource(stack, resource string, enrichment []string) error {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.stacks = append(y.stacks, newReportRow(stack, resource, enrichment))
+	return nil
+}
+
+func (y *yamlReportWriter) WriteOrphan(section string, row []string) error {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.orphans[section] = append(y.orphans[section], rowFromFlat(row))
+	return nil
+}
+
+func (y *yamlReportWriter) Close() error {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	doc := struct {
+		Stacks  []reportRow            `yaml:"stacks"`
+		Orphans map[string][]reportRow `yaml:"orphans"`
+	}{Stacks: y.stacks, Orphans: y.orphans}
+	enc := yaml.NewEncoder(y.w)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if y.closer != nil {
+		return y.closer.Close()
+	}
+	return nil
+}
+
+// parquetReportWriter buffers every row in memory and writes a single
+// Parquet file on Close, for loading directly into Athena/Redshift.
+type parquetReportWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	rows   []reportRow
+}
+
+func newParquetReportWriter(w io.Writer, closer io.Closer) *parquetReportWriter {
+	return &parquetReportWriter{w: w, closer: closer}
+}
+
+func (p *parquetReportWriter) WriteHeader() error { return nil }
+
+func (p *parquetReportWriter) WriteStackResource(stack, resource string, enrichment []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rows = append(p.rows, newReportRow(stack, resource, enrichment))
+	return nil
+}
+
+func (p *parquetReportWriter) WriteOrphan(section string, row []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rows = append(p.rows, rowFromFlat(row))
+	return nil
+}
+
+func (p *parquetReportWriter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := parquet.Write[reportRow](p.w, p.rows); err != nil {
+		return err
+	}
+	if p.closer != nil {
+		return p.closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// errAll is the synthetic "catch everything" error code, matching the
+// States.ALL convention used by Step Functions Retry blocks.
+const errAll = "States.ALL"
+
+// Retrier mirrors the Retry block of a Step Functions state machine: a list
+// of AWS SDK error codes it applies to, plus the backoff schedule to use
+// when one of those codes comes back from the call being wrapped.
+type Retrier struct {
+	ErrorEquals     []string `yaml:"ErrorEquals"`
+	IntervalSeconds int      `yaml:"IntervalSeconds"`
+	MaxAttempts     int      `yaml:"MaxAttempts"`
+	BackoffRate     float64  `yaml:"BackoffRate"`
+}
+
+// RetryConfig is the top level shape of the --retry-config YAML file.
+type RetryConfig struct {
+	Retriers []Retrier `yaml:"Retriers"`
+}
+
+// defaultRetriers is used when no --retry-config file is given.
+func defaultRetriers() []Retrier {
+	return []Retrier{
+		{
+			ErrorEquals:     []string{"Throttling", "ThrottlingException", "RequestLimitExceeded"},
+			IntervalSeconds: 2,
+			MaxAttempts:     5,
+			BackoffRate:     2.0,
+		},
+		{
+			ErrorEquals:     []string{errAll},
+			IntervalSeconds: 1,
+			MaxAttempts:     2,
+			BackoffRate:     1.0,
+		},
+	}
+}
+
+// LoadRetryConfig reads a YAML file shaped like RetryConfig. An empty path
+// falls back to defaultRetriers so the tool works the same out of the box.
+func LoadRetryConfig(path string) ([]Retrier, error) {
+	if path == "" {
+		return defaultRetriers(), nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading retry config %s: %w", path, err)
+	}
+	var cfg RetryConfig
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing retry config %s: %w", path, err)
+	}
+	if len(cfg.Retriers) == 0 {
+		return defaultRetriers(), nil
+	}
+	return cfg.Retriers, nil
+}
+
+// apiError is the subset of the smithy APIError interface we need to match
+// an AWS SDK error against a Retrier's ErrorEquals list.
+type apiError interface {
+	ErrorCode() string
+}
+
+// matches reports whether r applies to err.
+func (r Retrier) matches(err error) bool {
+	var ae apiError
+	if !errors.As(err, &ae) {
+		return false
+	}
+	for _, code := range r.ErrorEquals {
+		if code == errAll || code == ae.ErrorCode() {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorCode extracts the AWS error code from err for callers that want to
+// tag a metric or log line with it (e.g. Metrics.AddScanError), falling
+// back to "unknown" for errors that don't carry one (context cancellation,
+// config/network failures, ...).
+func ErrorCode(err error) string {
+	var ae apiError
+	if !errors.As(err, &ae) {
+		return "unknown"
+	}
+	return ae.ErrorCode()
+}
+
+// Attempts records how many tries Do made before returning, so callers can
+// surface it alongside a result (e.g. as an extra column in the CSV).
+type Attempts struct {
+	Count int
+}
+
+// Do runs fn, retrying it according to retriers when fn returns a matching
+// AWS error. Retriers are walked in order on each failure, the same way a
+// Step Functions Retry block is evaluated top to bottom; the first one that
+// matches decides whether (and how long) to wait before trying again. The
+// sleep between attempts is IntervalSeconds * BackoffRate^(attempt-1),
+// plus up to 20% jitter so a fleet of workers doesn't retry in lockstep.
+func Do(ctx context.Context, retriers []Retrier, fn func() error) (Attempts, error) {
+	attempts := Attempts{}
+	for {
+		attempts.Count++
+		err := fn()
+		if err == nil {
+			return attempts, nil
+		}
+
+		retrier, ok := matchingRetrier(retriers, err)
+		if !ok || attempts.Count >= retrier.MaxAttempts {
+			return attempts, err
+		}
+
+		interval := time.Duration(float64(retrier.IntervalSeconds) * math.Pow(retrier.BackoffRate, float64(attempts.Count-1)) * float64(time.Second))
+		interval += time.Duration(rand.Float64() * 0.2 * float64(interval))
+
+		select {
+		case <-ctx.Done():
+			return attempts, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func matchingRetrier(retriers []Retrier, err error) (Retrier, bool) {
+	for _, r := range retriers {
+		if r.matches(err) {
+			return r, true
+		}
+	}
+	return Retrier{}, false
+}
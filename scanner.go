@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"go.uber.org/zap"
+)
+
+// Scanner drives a single account+region worth of resource discovery. A
+// multi-account, multi-region run is just one Scanner per (account, region)
+// pair, fanned out over a bounded worker pool by scanFleet.
+// Collector-based resource types (IAM, S3, Lambda, ...) take s.Cfg directly
+// rather than going through Scanner, since they're selected dynamically via
+// --collectors.
+type Scanner struct {
+	Cfg      aws.Config
+	Account  string
+	Region   string
+	Retriers []Retrier
+	Metrics  *Metrics
+	Logger   *zap.SugaredLogger
+
+	cfnClient *cloudformation.Client
+}
+
+// NewScanner builds the service clients for cfg once so every call on the
+// returned Scanner reuses them, and tags every log line Scanner methods
+// emit with this account and region. metrics is nil outside --periodic
+// mode, since a one-shot scan doesn't serve /metrics.
+func NewScanner(cfg aws.Config, account, region string, retriers []Retrier, metrics *Metrics, sugar *zap.SugaredLogger) *Scanner {
+	return &Scanner{
+		Cfg:       cfg,
+		Account:   account,
+		Region:    region,
+		Retriers:  retriers,
+		Metrics:   metrics,
+		Logger:    sugar.With(zap.String("account", account), zap.String("region", region)),
+		cfnClient: cloudformation.NewFromConfig(cfg),
+	}
+}
+
+// Stacks returns every top level CloudFormation stack ID in this Scanner's
+// account and region.
+func (s *Scanner) Stacks(ctx context.Context) []string {
+	return findallcloudformationstacks(ctx, s.cfnClient, "", s.Retriers, s.Metrics, s.Logger)
+}
+
+// StackResources returns the flattened (including nested stacks) resource
+// list for a single stack.
+func (s *Scanner) StackResources(ctx context.Context, stackID string) []awscfnresource {
+	return getallcfnresources(ctx, s.cfnClient, stackID, s.Retriers, s.Metrics, s.Logger)
+}
+
+// accountProfile is one credential source the orchestrator fans out over:
+// either a named profile or an STS role to assume on top of the base
+// profile.
+type accountProfile struct {
+	Profile    string
+	AssumeRole string
+	Account    string
+}
+
+// resolveAccounts expands --profiles and --assume-role into the list of
+// credential sources to scan. Each profile is its own account; --assume-role
+// adds one more entry that chains a single, fixed AssumeRole on top of the
+// base profile — e.g. an OrgReader role already granted in one other
+// account. It does not enumerate an AWS Organization's accounts itself:
+// only the --profiles entries (or the default credentials) and that one
+// --assume-role entry are scanned.
+func resolveAccounts(profilesFlag, assumeRoleFlag string) []accountProfile {
+	accounts := []accountProfile{}
+	if profilesFlag == "" {
+		accounts = append(accounts, accountProfile{Account: "default"})
+	} else {
+		for _, p := range strings.Split(profilesFlag, ",") {
+			accounts = append(accounts, accountProfile{Profile: p, Account: p})
+		}
+	}
+	if assumeRoleFlag != "" {
+		accounts = append(accounts, accountProfile{AssumeRole: assumeRoleFlag, Account: assumeRoleFlag})
+	}
+	return accounts
+}
+
+// resolveRegions expands the --regions flag. "all" is resolved via
+// ec2.DescribeRegions against baseCfg; anything else is taken as a literal
+// comma-separated list.
+func resolveRegions(ctx context.Context, baseCfg aws.Config, regionsFlag string) ([]string, error) {
+	if regionsFlag == "" {
+		return []string{baseCfg.Region}, nil
+	}
+	if regionsFlag != "all" {
+		return strings.Split(regionsFlag, ","), nil
+	}
+	ec2client := ec2.NewFromConfig(baseCfg)
+	output, err := ec2client.DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("describing regions: %w", err)
+	}
+	regions := make([]string, 0, len(output.Regions))
+	for _, r := range output.Regions {
+		regions = append(regions, aws.ToString(r.RegionName))
+	}
+	return regions, nil
+}
+
+// loadScannerConfig builds the aws.Config for a single accountProfile and
+// region, chaining an STS AssumeRole on top of the base profile when the
+// accountProfile carries one.
+func loadScannerConfig(ctx context.Context, baseProfile string, acct accountProfile, region string) (aws.Config, error) {
+	profile := acct.Profile
+	if profile == "" {
+		profile = baseProfile
+	}
+
+	var opts []func(*config.LoadOptions) error
+	if profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(profile))
+	}
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	if acct.AssumeRole != "" {
+		stsclient := sts.NewFromConfig(cfg)
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(stsclient, acct.AssumeRole))
+	}
+
+	return cfg, nil
+}
+
+// collectorBucket holds one Collector's results for a scan, as the rows
+// they're bucketed and written in, so entries can be removed as they're
+// matched against CloudFormation stack resources.
+type collectorBucket struct {
+	name string
+	rows [][]string
+}
+
+// runScan performs the same CloudFormation-vs-everything-else bucketing the
+// single-account tool always has, but against s's account and region, runs
+// every requested Collector instead of just IAM roles and log groups, tags
+// every row with Account and Region, and writes through w so concurrent
+// Scanners can share one output regardless of its format.
+func runScan(ctx context.Context, s *Scanner, loadFileName string, collectors []Collector, w ReportWriter, progress *Progress, sugar *zap.SugaredLogger) {
+	sugar.Infof("Starting scan for account=%s region=%s", s.Account, s.Region)
+
+	stacklist := s.Stacks(ctx)
+	progress.AddStacks(len(stacklist))
+
+	buckets := make([]collectorBucket, 0, len(collectors))
+	for _, c := range collectors {
+		resources, err := c.Collect(ctx, s.Cfg, s.Retriers)
+		if err != nil {
+			s.Metrics.AddScanError(ErrorCode(err))
+			sugar.Errorf("Collector %s failed for account=%s region=%s: %v", c.Name(), s.Account, s.Region, err)
+			continue
+		}
+		rows := make([][]string, 0, len(resources))
+		for _, r := range resources {
+			rows = append(rows, r.row())
+		}
+		buckets = append(buckets, collectorBucket{name: c.Name(), rows: rows})
+	}
+
+	record, err := loadcsv(loadFileName, sugar)
+	if err != nil {
+		sugar.Errorf("Failed loading CSV file %s for account=%s region=%s: %v; continuing without enrichment data", loadFileName, s.Account, s.Region, err)
+		record = [][]string{}
+	}
+
+	for countstack, stackId := range stacklist {
+		if ctx.Err() != nil {
+			sugar.Warnf("Scan for account=%s region=%s interrupted after %d/%d stacks", s.Account, s.Region, countstack, len(stacklist))
+			break
+		}
+
+		cfnsearchresult, countrecord := searchfromrecord(record, stackId, sugar)
+		if cfnsearchresult != nil {
+			_ = w.WriteStackResource(fmt.Sprint(countstack+1), stackId, []string{"", cfnsearchresult[1], cfnsearchresult[2], cfnsearchresult[3], cfnsearchresult[4], "", s.Account, s.Region})
+			record = removesliceentry(record, countrecord, sugar)
+		} else {
+			_ = w.WriteStackResource(fmt.Sprint(countstack+1), stackId, []string{"", "", "", "", "", "", s.Account, s.Region})
+		}
+
+		awscfnresourceslice := s.StackResources(ctx, stackId)
+		count := 0
+		for countcfn, resource := range awscfnresourceslice {
+			searchresult, countrecord := searchfromrecord(record, resource.PhysicalResourceId, sugar)
+			if searchresult != nil {
+				record = removesliceentry(record, countrecord, sugar)
+				_ = w.WriteStackResource(fmt.Sprintf("%d.%d", countstack+1, count+1), resource.PhysicalResourceId, []string{resource.LogicalResourceId, searchresult[1], searchresult[2], searchresult[3], searchresult[4], fmt.Sprint(resource.Attempts), s.Account, s.Region})
+				count++
+				continue
+			}
+
+			if found, bucketIdx, rowIdx := searchBuckets(buckets, resource.PhysicalResourceId, sugar); found != nil {
+				buckets[bucketIdx].rows = removesliceentry(buckets[bucketIdx].rows, rowIdx, sugar)
+				// found is a Resource.row(): [Name, ID, ARN, Service, Type, Region].
+				_ = w.WriteStackResource(fmt.Sprintf("%d.%d", countstack+1, count+1), resource.PhysicalResourceId, []string{resource.LogicalResourceId, found[0], found[3], found[4], found[5], fmt.Sprint(resource.Attempts), s.Account, s.Region})
+				count++
+				continue
+			}
+
+			// if it doesn't exist in the csv record or any collector bucket,
+			// just print it without adding any additional information
+			_ = w.WriteStackResource(fmt.Sprintf("%d.%d", countstack+1, countcfn+1), resource.PhysicalResourceId, []string{resource.LogicalResourceId, "", "", "", "", fmt.Sprint(resource.Attempts), s.Account, s.Region})
+		}
+		progress.StackDone()
+	}
+
+	for countrecord, records := range record {
+		_ = w.WriteOrphan("csv", []string{fmt.Sprint(countrecord + 1), records[0], "", records[1], records[2], records[3], records[4], "", s.Account, s.Region})
+	}
+
+	for _, bucket := range buckets {
+		for countrow, row := range bucket.rows {
+			// row is a Resource.row(): [Name, ID, ARN, Service, Type, Region].
+			_ = w.WriteOrphan(bucket.name, []string{fmt.Sprint(countrow + 1), row[2], "", row[0], row[3], row[4], row[5], "", s.Account, s.Region})
+		}
+	}
+
+	sugar.Infof("Finished scan for account=%s region=%s", s.Account, s.Region)
+}
+
+// scanFleet fans out one Scanner per (account, region) pair over a bounded
+// worker pool, writing every result through w, and blocks until they've all
+// finished or ctx is cancelled. It's the orchestration main runs once for a
+// plain scan and Periodic re-runs on a schedule with a fresh ReportWriter
+// each cycle.
+func scanFleet(ctx context.Context, awsProfile string, accounts []accountProfile, regions []string, maxConcurrency int, retriers []Retrier, loadFileName string, collectors []Collector, w ReportWriter, progress *Progress, metrics *Metrics, sugar *zap.SugaredLogger) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrency)
+	for _, acct := range accounts {
+		for _, region := range regions {
+			acct, region := acct, region
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				if ctx.Err() != nil {
+					return
+				}
+
+				cfg, err := loadScannerConfig(ctx, awsProfile, acct, region)
+				if err != nil {
+					sugar.Errorf("Failed loading config for account=%s region=%s: %v", acct.Account, region, err)
+					return
+				}
+
+				scanner := NewScanner(cfg, acct.Account, region, retriers, metrics, sugar)
+				runScan(ctx, scanner, loadFileName, collectors, w, progress, sugar)
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// searchBuckets is searchfromrecord fanned out across every collector
+// bucket in order, stopping at the first match (mirroring the original
+// IAM-then-CloudWatch-Logs lookup order).
+func searchBuckets(buckets []collectorBucket, search string, sugar *zap.SugaredLogger) ([]string, int, int) {
+	for bucketIdx, bucket := range buckets {
+		if row, rowIdx := searchfromrecord(bucket.rows, search, sugar); row != nil {
+			return row, bucketIdx, rowIdx
+		}
+	}
+	return nil, -1, -1
+}
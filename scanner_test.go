@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"go.uber.org/zap"
+)
+
+// fakeCollector returns a fixed set of Resources, so runScan's
+// collector-bucket matching/orphan paths can be exercised without a real
+// AWS endpoint.
+type fakeCollector struct {
+	name      string
+	resources []Resource
+}
+
+func (f fakeCollector) Name() string { return f.name }
+
+func (f fakeCollector) Collect(ctx context.Context, cfg aws.Config, retriers []Retrier) ([]Resource, error) {
+	return f.resources, nil
+}
+
+type stackLine struct {
+	Kind string `json:"kind"`
+	reportRow
+}
+
+type orphanLine struct {
+	Kind    string `json:"kind"`
+	Section string `json:"section"`
+	reportRow
+}
+
+// TestRunScanCollectorEnrichmentColumns asserts a matched and an orphaned
+// collector Resource round-trip through a ReportWriter with their columns
+// in the right slots: Service/Type/Region must come from the Resource
+// itself, not from adjacent fields in Resource.row().
+func TestRunScanCollectorEnrichmentColumns(t *testing.T) {
+	stacksPage := `<ListStacksResponse><ListStacksResult><StackSummaries><member><StackId>arn:aws:cloudformation:us-west-2:123456789012:stack/test/abc</StackId><StackName>test</StackName><StackStatus>CREATE_COMPLETE</StackStatus></member></StackSummaries></ListStacksResult></ListStacksResponse>`
+	resourcesPage := `<ListStackResourcesResponse><ListStackResourcesResult><StackResourceSummaries><member><LogicalResourceId>MatchedInstance</LogicalResourceId><PhysicalResourceId>i-matched123</PhysicalResourceId><ResourceType>AWS::EC2::Instance</ResourceType><ResourceStatus>CREATE_COMPLETE</ResourceStatus><LastUpdatedTimestamp>2024-01-01T00:00:00Z</LastUpdatedTimestamp></member></StackResourceSummaries></ListStackResourcesResult></ListStackResourcesResponse>`
+	cfg := testConfig(&pagedTransport{pages: []string{stacksPage, resourcesPage}, contentType: "text/xml"})
+
+	sugar := zap.NewNop().Sugar()
+	scanner := NewScanner(cfg, "111111111111", "us-east-1", nil, nil, sugar)
+
+	collector := fakeCollector{
+		name: "ec2",
+		resources: []Resource{
+			{Name: "i-matched123", ID: "i-matched123", ARN: "arn:aws:ec2:us-west-2:123456789012:instance/i-matched123", Service: "EC2", Type: "Instance", Region: "us-west-2"},
+			{Name: "i-orphan456", ID: "i-orphan456", ARN: "arn:aws:ec2:us-west-2:123456789012:instance/i-orphan456", Service: "EC2", Type: "Instance", Region: "us-west-2"},
+		},
+	}
+
+	var buf bytes.Buffer
+	w := newNDJSONReportWriter(&buf, nil)
+	progress := NewProgress(false)
+
+	runScan(context.Background(), scanner, "", []Collector{collector}, w, progress, sugar)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	var stack *stackLine
+	var orphan *orphanLine
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		var kind struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal(raw, &kind); err != nil {
+			t.Fatalf("decode kind: %v", err)
+		}
+		switch kind.Kind {
+		case "stack":
+			var line stackLine
+			if err := json.Unmarshal(raw, &line); err != nil {
+				t.Fatalf("decode stack line: %v", err)
+			}
+			if line.ResourceID == "i-matched123" {
+				stack = &line
+			}
+		case "orphan":
+			var line orphanLine
+			if err := json.Unmarshal(raw, &line); err != nil {
+				t.Fatalf("decode orphan line: %v", err)
+			}
+			if line.Name == "i-orphan456" {
+				orphan = &line
+			}
+		}
+	}
+
+	if stack == nil {
+		t.Fatal("matched resource's stack-resource line not found")
+	}
+	if stack.LogicalID != "MatchedInstance" {
+		t.Errorf("LogicalID = %q, want MatchedInstance", stack.LogicalID)
+	}
+	if stack.Name != "i-matched123" {
+		t.Errorf("Name = %q, want i-matched123", stack.Name)
+	}
+	if stack.Service != "EC2" {
+		t.Errorf("Service = %q, want EC2", stack.Service)
+	}
+	if stack.Type != "Instance" {
+		t.Errorf("Type = %q, want Instance", stack.Type)
+	}
+	if stack.Region != "us-west-2" {
+		t.Errorf("Region = %q, want us-west-2", stack.Region)
+	}
+	if stack.Account != "111111111111" {
+		t.Errorf("Account = %q, want 111111111111", stack.Account)
+	}
+	if stack.ScanRegion != "us-east-1" {
+		t.Errorf("ScanRegion = %q, want us-east-1", stack.ScanRegion)
+	}
+
+	if orphan == nil {
+		t.Fatal("orphan resource's line not found")
+	}
+	if orphan.Section != "ec2" {
+		t.Errorf("Section = %q, want ec2", orphan.Section)
+	}
+	if orphan.ResourceID != "arn:aws:ec2:us-west-2:123456789012:instance/i-orphan456" {
+		t.Errorf("ResourceID = %q, want the orphan's ARN", orphan.ResourceID)
+	}
+	if orphan.Service != "EC2" {
+		t.Errorf("Service = %q, want EC2", orphan.Service)
+	}
+	if orphan.Type != "Instance" {
+		t.Errorf("Type = %q, want Instance", orphan.Type)
+	}
+	if orphan.Region != "us-west-2" {
+		t.Errorf("Region = %q, want us-west-2", orphan.Region)
+	}
+}